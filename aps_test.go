@@ -0,0 +1,202 @@
+package apns
+
+import "testing"
+
+func TestAlertIsZero(t *testing.T) {
+	tests := []struct {
+		name  string
+		alert Alert
+		zero  bool
+	}{
+		{"empty", Alert{}, true},
+		{"body", Alert{Body: "hi"}, false},
+		{"subtitle", Alert{Subtitle: "hi"}, false},
+		{"loc key", Alert{LocKey: "k"}, false},
+		{"loc args", Alert{LocArgs: []string{"a"}}, false},
+		{"title loc key", Alert{TitleLocKey: "k"}, false},
+		{"title loc args", Alert{TitleLocArgs: []string{"a"}}, false},
+		{"action loc key", Alert{ActionLocKey: "k"}, false},
+		{"launch image", Alert{LaunchImage: "img"}, false},
+		{"title only", Alert{Title: "t"}, true}, // Title is intentionally not part of isZero, matching the original implementation
+		{"action only", Alert{Action: "a"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.alert.isZero(); got != tt.zero {
+				t.Errorf("Alert%+v.isZero() = %v, want %v", tt.alert, got, tt.zero)
+			}
+		})
+	}
+}
+
+func TestAPSSerializeForAPNSNewFields(t *testing.T) {
+	score := 0.5
+
+	aps := APS{
+		Alert:             Alert{Body: "hi"},
+		MutableContent:    1,
+		ThreadID:          "thread-1",
+		TargetContentID:   "window-1",
+		InterruptionLevel: "time-sensitive",
+		RelevanceScore:    &score,
+	}
+
+	data := aps.serializeForAPNS()
+
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"mutable-content", 1},
+		{"thread-id", "thread-1"},
+		{"target-content-id", "window-1"},
+		{"interruption-level", "time-sensitive"},
+		{"relevance-score", &score},
+	}
+
+	for _, tt := range tests {
+		got, ok := data[tt.key]
+		if !ok {
+			t.Errorf("serializeForAPNS() missing key %q", tt.key)
+			continue
+		}
+		if tt.key == "relevance-score" {
+			if got.(*float64) != tt.want.(*float64) {
+				t.Errorf("data[%q] = %v, want %v", tt.key, got, tt.want)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("data[%q] = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestAPSSerializeForAPNSOmitsZeroNewFields(t *testing.T) {
+	aps := APS{Alert: Alert{Body: "hi"}}
+
+	data := aps.serializeForAPNS()
+
+	for _, key := range []string{"mutable-content", "thread-id", "target-content-id", "interruption-level", "relevance-score"} {
+		if _, ok := data[key]; ok {
+			t.Errorf("serializeForAPNS() unexpectedly set %q on a zero-value APS", key)
+		}
+	}
+}
+
+func TestNotificationValidateBackgroundPush(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       func() Notification
+		wantErr bool
+	}{
+		{
+			name: "background without content-available is rejected",
+			n: func() Notification {
+				n := NewNotification()
+				n.PushType = PushTypeBackground
+				return n
+			},
+			wantErr: true,
+		},
+		{
+			name: "background with an alert is rejected",
+			n: func() Notification {
+				n := NewNotification()
+				n.PushType = PushTypeBackground
+				n.Payload.APS.ContentAvailable = 1
+				n.Payload.APS.Alert.Body = "hi"
+				return n
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid background push",
+			n: func() Notification {
+				n := NewNotification()
+				n.PushType = PushTypeBackground
+				n.Payload.APS.ContentAvailable = 1
+				return n
+			},
+			wantErr: false,
+		},
+		{
+			name: "alert push is unaffected by the background rule",
+			n: func() Notification {
+				n := NewNotification()
+				n.Payload.APS.Alert.Body = "hi"
+				return n
+			},
+			wantErr: false,
+		},
+		{
+			name: "inferred background push (content-available, no alert) is validated the same way",
+			n: func() Notification {
+				n := NewNotification()
+				n.Payload.APS.ContentAvailable = 1
+				return n
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.n().Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolvePushType(t *testing.T) {
+	tests := []struct {
+		name string
+		n    func() Notification
+		want PushType
+	}{
+		{
+			name: "explicit push type wins",
+			n: func() Notification {
+				n := NewNotification()
+				n.PushType = PushTypeVoIP
+				n.Payload.APS.Alert.Body = "hi"
+				return n
+			},
+			want: PushTypeVoIP,
+		},
+		{
+			name: "content-available with no alert infers background",
+			n: func() Notification {
+				n := NewNotification()
+				n.Payload.APS.ContentAvailable = 1
+				return n
+			},
+			want: PushTypeBackground,
+		},
+		{
+			name: "alert body defaults to alert",
+			n: func() Notification {
+				n := NewNotification()
+				n.Payload.APS.Alert.Body = "hi"
+				return n
+			},
+			want: PushTypeAlert,
+		},
+		{
+			name: "no payload defaults to alert",
+			n:    func() Notification { return Notification{} },
+			want: PushTypeAlert,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.n().resolvePushType(); got != tt.want {
+				t.Errorf("resolvePushType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package apns
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error represents a failure to deliver a single notification, whether it
+// was reported by the legacy binary protocol's status byte or the HTTP/2
+// provider API's JSON "reason". It lets callers discriminate "retry
+// later" from "drop it, the payload is bad" from "the device token is
+// dead, stop sending to it" without caring which transport produced the
+// failure.
+type Error interface {
+	error
+
+	// Unsubscribe reports whether the device token should be removed
+	// from the caller's database because Apple considers it
+	// permanently invalid.
+	Unsubscribe() bool
+}
+
+type apnsError struct {
+	reason      string
+	message     string
+	unsubscribe bool
+}
+
+func (e *apnsError) Error() string     { return e.message }
+func (e *apnsError) Unsubscribe() bool { return e.unsubscribe }
+
+var (
+	ErrProcessing           Error = &apnsError{reason: "ProcessingError", message: "apns: internal processing error, retry later"}
+	ErrMissingDeviceToken   Error = &apnsError{reason: "MissingDeviceToken", message: "apns: missing device token"}
+	ErrMissingTopic         Error = &apnsError{reason: "MissingTopic", message: "apns: missing topic"}
+	ErrMissingPayload       Error = &apnsError{reason: "PayloadEmpty", message: "apns: missing payload"}
+	ErrInvalidTokenSize     Error = &apnsError{reason: "BadDeviceToken", message: "apns: invalid device token size"}
+	ErrInvalidTopicSize     Error = &apnsError{reason: "BadTopic", message: "apns: invalid topic size"}
+	ErrInvalidPayloadSize   Error = &apnsError{reason: "PayloadTooLarge", message: "apns: invalid payload size"}
+	ErrInvalidToken         Error = &apnsError{reason: "BadDeviceToken", message: "apns: invalid device token", unsubscribe: true}
+	ErrUnregistered         Error = &apnsError{reason: "Unregistered", message: "apns: device token is no longer registered", unsubscribe: true}
+	ErrShutdown             Error = &apnsError{reason: "Shutdown", message: "apns: server is shutting down, resend later"}
+	ErrTooManyRequests      Error = &apnsError{reason: "TooManyRequests", message: "apns: too many requests for this device token, retry later"}
+	ErrExpiredProviderToken Error = &apnsError{reason: "ExpiredProviderToken", message: "apns: provider token expired, refresh and retry"}
+	ErrUnknown              Error = &apnsError{reason: "Unknown", message: "apns: unknown error"}
+
+	// ErrPayloadTooLarge is returned by Payload.Validate (and by anything
+	// that calls it before touching the network) when the serialized
+	// payload exceeds the caller-supplied size limit. It is distinct
+	// from ErrInvalidPayloadSize, which reports that APNS itself
+	// rejected an oversized payload we sent anyway.
+	ErrPayloadTooLarge Error = &apnsError{reason: "PayloadTooLarge", message: "apns: payload exceeds the maximum size"}
+)
+
+// StatusToError maps a legacy binary-protocol status byte, as returned in
+// the 6-byte error response APNS sends before closing the connection, to
+// an Error. It returns nil for status 0 ("no errors encountered").
+func StatusToError(status byte) Error {
+	switch status {
+	case 0:
+		return nil
+	case 1:
+		return ErrProcessing
+	case 2:
+		return ErrMissingDeviceToken
+	case 3:
+		return ErrMissingTopic
+	case 4:
+		return ErrMissingPayload
+	case 5:
+		return ErrInvalidTokenSize
+	case 6:
+		return ErrInvalidTopicSize
+	case 7:
+		return ErrInvalidPayloadSize
+	case 8:
+		return ErrInvalidToken
+	case 10:
+		return ErrShutdown
+	default:
+		return ErrUnknown
+	}
+}
+
+// reasonToError maps an HTTP/2 provider API "reason" string to an Error.
+// Reasons APNS can return that have no legacy-protocol equivalent, such as
+// ExpiredProviderToken, still come back through the same taxonomy so
+// callers can handle both transports identically.
+func reasonToError(reason string) Error {
+	switch reason {
+	case "BadDeviceToken":
+		return ErrInvalidToken
+	case "Unregistered":
+		return ErrUnregistered
+	case "MissingDeviceToken":
+		return ErrMissingDeviceToken
+	case "MissingTopic":
+		return ErrMissingTopic
+	case "PayloadEmpty":
+		return ErrMissingPayload
+	case "BadTopic":
+		return ErrInvalidTopicSize
+	case "PayloadTooLarge":
+		return ErrInvalidPayloadSize
+	case "TooManyRequests":
+		return ErrTooManyRequests
+	case "ExpiredProviderToken", "InvalidProviderToken", "InvalidSigningKey":
+		return ErrExpiredProviderToken
+	case "IdleTimeout", "Shutdown", "InternalServerError", "ServiceUnavailable":
+		return ErrShutdown
+	case "":
+		return ErrUnknown
+	default:
+		return &apnsError{reason: reason, message: fmt.Sprintf("apns: %s", reason)}
+	}
+}
+
+// IsUnsubscribe reports whether err indicates that Apple considers the
+// device token permanently invalid, regardless of whether it came from
+// the legacy binary protocol or the HTTP/2 provider API. Callers can use
+// this single check to drive token cleanup.
+func IsUnsubscribe(err error) bool {
+	var e Error
+	if errors.As(err, &e) {
+		return e.Unsubscribe()
+	}
+	return false
+}
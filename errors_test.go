@@ -0,0 +1,96 @@
+package apns
+
+import "testing"
+
+func TestStatusToError(t *testing.T) {
+	tests := []struct {
+		status      byte
+		want        Error
+		unsubscribe bool
+	}{
+		{0, nil, false},
+		{1, ErrProcessing, false},
+		{2, ErrMissingDeviceToken, false},
+		{3, ErrMissingTopic, false},
+		{4, ErrMissingPayload, false},
+		{5, ErrInvalidTokenSize, false},
+		{6, ErrInvalidTopicSize, false},
+		{7, ErrInvalidPayloadSize, false},
+		{8, ErrInvalidToken, true},
+		{10, ErrShutdown, false},
+		{255, ErrUnknown, false},
+		{42, ErrUnknown, false},
+	}
+
+	for _, tt := range tests {
+		got := StatusToError(tt.status)
+		if got != tt.want {
+			t.Errorf("StatusToError(%d) = %v, want %v", tt.status, got, tt.want)
+			continue
+		}
+		if got != nil && got.Unsubscribe() != tt.unsubscribe {
+			t.Errorf("StatusToError(%d).Unsubscribe() = %v, want %v", tt.status, got.Unsubscribe(), tt.unsubscribe)
+		}
+	}
+}
+
+func TestReasonToError(t *testing.T) {
+	tests := []struct {
+		reason      string
+		want        Error
+		unsubscribe bool
+	}{
+		{"BadDeviceToken", ErrInvalidToken, true},
+		{"Unregistered", ErrUnregistered, true},
+		{"MissingDeviceToken", ErrMissingDeviceToken, false},
+		{"MissingTopic", ErrMissingTopic, false},
+		{"PayloadEmpty", ErrMissingPayload, false},
+		{"BadTopic", ErrInvalidTopicSize, false},
+		{"PayloadTooLarge", ErrInvalidPayloadSize, false},
+		{"TooManyRequests", ErrTooManyRequests, false},
+		{"ExpiredProviderToken", ErrExpiredProviderToken, false},
+		{"InvalidProviderToken", ErrExpiredProviderToken, false},
+		{"InvalidSigningKey", ErrExpiredProviderToken, false},
+		{"IdleTimeout", ErrShutdown, false},
+		{"Shutdown", ErrShutdown, false},
+		{"InternalServerError", ErrShutdown, false},
+		{"ServiceUnavailable", ErrShutdown, false},
+		{"", ErrUnknown, false},
+	}
+
+	for _, tt := range tests {
+		got := reasonToError(tt.reason)
+		if got != tt.want {
+			t.Errorf("reasonToError(%q) = %v, want %v", tt.reason, got, tt.want)
+			continue
+		}
+		if got.Unsubscribe() != tt.unsubscribe {
+			t.Errorf("reasonToError(%q).Unsubscribe() = %v, want %v", tt.reason, got.Unsubscribe(), tt.unsubscribe)
+		}
+	}
+
+	if got := reasonToError("SomeFutureReason"); got.Unsubscribe() {
+		t.Errorf("reasonToError(%q).Unsubscribe() = true, want false for an unrecognized reason", "SomeFutureReason")
+	}
+}
+
+func TestIsUnsubscribe(t *testing.T) {
+	if IsUnsubscribe(nil) {
+		t.Error("IsUnsubscribe(nil) = true, want false")
+	}
+	if IsUnsubscribe(ErrShutdown) {
+		t.Error("IsUnsubscribe(ErrShutdown) = true, want false")
+	}
+	if !IsUnsubscribe(ErrInvalidToken) {
+		t.Error("IsUnsubscribe(ErrInvalidToken) = false, want true")
+	}
+	if !IsUnsubscribe(ErrUnregistered) {
+		t.Error("IsUnsubscribe(ErrUnregistered) = false, want true")
+	}
+	if IsUnsubscribe(&HTTP2Error{Reason: "ExpiredProviderToken"}) {
+		t.Error("IsUnsubscribe(ExpiredProviderToken HTTP2Error) = true, want false")
+	}
+	if !IsUnsubscribe(&HTTP2Error{Reason: "Unregistered"}) {
+		t.Error("IsUnsubscribe(Unregistered HTTP2Error) = false, want true")
+	}
+}
@@ -0,0 +1,120 @@
+package apns
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	FeedbackHostProduction = "feedback.push.apple.com:2196"
+	FeedbackHostSandbox    = "feedback.sandbox.push.apple.com:2196"
+)
+
+// FeedbackTuple is a single record from Apple's feedback service: a device
+// token that a notification could not be delivered to because the app has
+// been uninstalled, and when Apple last observed that.
+type FeedbackTuple struct {
+	Timestamp   time.Time
+	DeviceToken string
+}
+
+// FeedbackClient reads expired-token tuples from the legacy feedback
+// service, which uses the same TLS certificate as a binary-protocol
+// sender.
+type FeedbackClient struct {
+	Cert tls.Certificate
+	Host string // defaults to FeedbackHostProduction
+
+	// TLSConfig, if set, overrides the TLS configuration built from Cert.
+	// Most callers should leave this nil; it exists for tests that need
+	// to point Read at a mock server with its own certificate authority.
+	TLSConfig *tls.Config
+}
+
+// NewFeedbackClient creates a FeedbackClient that authenticates with cert,
+// the same certificate used to send notifications over the binary
+// protocol.
+func NewFeedbackClient(cert tls.Certificate, sandbox bool) *FeedbackClient {
+	host := FeedbackHostProduction
+	if sandbox {
+		host = FeedbackHostSandbox
+	}
+	return &FeedbackClient{Cert: cert, Host: host}
+}
+
+// Read connects to the feedback service and streams every tuple it sends.
+// Apple signals end of data by closing the connection, at which point both
+// channels are closed; callers should range over tuples until it closes,
+// then check errs for a non-nil error.
+func (c *FeedbackClient) Read(ctx context.Context) (<-chan FeedbackTuple, <-chan error) {
+	tuples := make(chan FeedbackTuple)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(tuples)
+		defer close(errs)
+
+		tlsConfig := c.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{Certificates: []tls.Certificate{c.Cert}}
+		}
+
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err := dialer.DialContext(ctx, "tcp", c.Host)
+		if err != nil {
+			errs <- fmt.Errorf("apns: dial feedback service: %s", err)
+			return
+		}
+		defer conn.Close()
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+			case <-done:
+			}
+		}()
+
+		if err := readFeedbackTuples(conn, tuples); err != nil && err != io.EOF {
+			errs <- err
+		}
+	}()
+
+	return tuples, errs
+}
+
+// readFeedbackTuples parses the feedback service's stream of
+// (timestamp uint32, tokenLen uint16, token [tokenLen]byte) tuples from r,
+// sending each to tuples until r is exhausted.
+func readFeedbackTuples(r io.Reader, tuples chan<- FeedbackTuple) error {
+	header := make([]byte, 6)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		ts := binary.BigEndian.Uint32(header[0:4])
+		tokLen := binary.BigEndian.Uint16(header[4:6])
+
+		token := make([]byte, tokLen)
+		if _, err := io.ReadFull(r, token); err != nil {
+			return err
+		}
+
+		tuples <- FeedbackTuple{
+			Timestamp:   time.Unix(int64(ts), 0),
+			DeviceToken: hex.EncodeToString(token),
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package apns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// newMockFeedbackServer starts a TLS listener that accepts one connection,
+// writes the given tuples to it and then closes it, mimicking Apple's
+// feedback service. It returns the address to dial and a matching
+// tls.Config a client can use to verify the server's self-signed cert.
+func newMockFeedbackServer(t *testing.T, tuples []FeedbackTuple) (addr string, clientConfig *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %s", err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, tup := range tuples {
+			header := make([]byte, 6)
+			binary.BigEndian.PutUint32(header[0:4], uint32(tup.Timestamp.Unix()))
+			tok, _ := hex.DecodeString(tup.DeviceToken)
+			binary.BigEndian.PutUint16(header[4:6], uint16(len(tok)))
+			conn.Write(header)
+			conn.Write(tok)
+		}
+	}()
+
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %s", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+
+	return ln.Addr().String(), &tls.Config{RootCAs: pool}
+}
+
+func TestFeedbackClientRead(t *testing.T) {
+	want := []FeedbackTuple{
+		{Timestamp: time.Unix(1700000000, 0), DeviceToken: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		{Timestamp: time.Unix(1700000100, 0), DeviceToken: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+	}
+
+	addr, clientConfig := newMockFeedbackServer(t, want)
+
+	c := &FeedbackClient{Host: addr, TLSConfig: clientConfig}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tuples, errs := c.Read(ctx)
+
+	var got []FeedbackTuple
+	for tup := range tuples {
+		got = append(got, tup)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d tuples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].DeviceToken != want[i].DeviceToken || !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("tuple %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
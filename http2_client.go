@@ -0,0 +1,283 @@
+package apns
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	HostProduction = "https://api.push.apple.com"
+	HostSandbox    = "https://api.sandbox.push.apple.com"
+)
+
+// HTTP2Client sends notifications through Apple's HTTP/2 provider API, the
+// successor to the legacy binary protocol that Notification.ToBinary
+// implements. A client authenticates either with a TLS certificate or with
+// a TokenSigner, and is safe for concurrent use.
+type HTTP2Client struct {
+	Host string
+
+	httpClient *http.Client
+	signer     *TokenSigner
+}
+
+// NewHTTP2ClientWithCert creates an HTTP2Client that authenticates with a
+// TLS client certificate, the same credential the legacy binary protocol
+// uses.
+func NewHTTP2ClientWithCert(cert tls.Certificate, sandbox bool) (*HTTP2Client, error) {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		},
+	}
+	if err := http2.ConfigureTransport(transport); err != nil {
+		return nil, fmt.Errorf("apns: configure http/2 transport: %s", err)
+	}
+
+	return &HTTP2Client{
+		Host:       host(sandbox),
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// NewHTTP2ClientWithToken creates an HTTP2Client that authenticates with a
+// .p8 token signer instead of a certificate.
+func NewHTTP2ClientWithToken(signer *TokenSigner, sandbox bool) (*HTTP2Client, error) {
+	transport := &http.Transport{}
+	if err := http2.ConfigureTransport(transport); err != nil {
+		return nil, fmt.Errorf("apns: configure http/2 transport: %s", err)
+	}
+
+	return &HTTP2Client{
+		Host:       host(sandbox),
+		httpClient: &http.Client{Transport: transport},
+		signer:     signer,
+	}, nil
+}
+
+func host(sandbox bool) string {
+	if sandbox {
+		return HostSandbox
+	}
+	return HostProduction
+}
+
+// Push sends a single notification and blocks until Apple has responded or
+// ctx is done. Push validates n first, returning ErrPayloadTooLarge
+// without touching the network if the serialized payload is too large; a
+// later non-nil error is either a transport failure or an *HTTP2Error
+// describing why Apple rejected the notification.
+func (c *HTTP2Client) Push(ctx context.Context, n Notification) error {
+	if err := n.validatePushType(); err != nil {
+		return err
+	}
+
+	var body []byte
+	if n.Payload != nil {
+		var err error
+		body, err = n.Payload.MarshalJSONForAPNS()
+		if err != nil {
+			return err
+		}
+		if len(body) > n.maxPayloadBytes() {
+			return ErrPayloadTooLarge
+		}
+	}
+
+	url := fmt.Sprintf("%s/3/device/%s", c.Host, n.DeviceToken)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if n.ID != "" {
+		req.Header.Set("apns-id", n.ID)
+	}
+	if n.Expiration != nil {
+		req.Header.Set("apns-expiration", strconv.FormatInt(n.Expiration.Unix(), 10))
+	}
+	if n.Priority != 0 {
+		req.Header.Set("apns-priority", strconv.Itoa(n.Priority))
+	}
+	if n.Topic != "" {
+		req.Header.Set("apns-topic", n.Topic)
+	}
+	if n.CollapseID != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseID)
+	}
+	req.Header.Set("apns-push-type", string(n.resolvePushType()))
+
+	if c.signer != nil {
+		token, err := c.signer.Token()
+		if err != nil {
+			return fmt.Errorf("apns: sign token: %s", err)
+		}
+		req.Header.Set("authorization", "bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	return parseHTTP2Error(resp)
+}
+
+// HTTP2Error is the structured form of an HTTP/2 provider API error
+// response: a JSON object carrying a "reason" string and, for a 410,
+// a "timestamp" marking when APNS last confirmed the device token invalid.
+type HTTP2Error struct {
+	StatusCode int
+	Reason     string
+	Timestamp  *time.Time
+}
+
+func (e *HTTP2Error) Error() string {
+	if e.Timestamp != nil {
+		return fmt.Sprintf("apns: %s (status %d, timestamp %s)", e.Reason, e.StatusCode, e.Timestamp.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("apns: %s (status %d)", e.Reason, e.StatusCode)
+}
+
+// Unsubscribe reports whether Reason indicates the device token is
+// permanently invalid, making HTTP2Error satisfy the Error interface.
+func (e *HTTP2Error) Unsubscribe() bool {
+	return reasonToError(e.Reason).Unsubscribe()
+}
+
+func parseHTTP2Error(resp *http.Response) error {
+	var body struct {
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return &HTTP2Error{StatusCode: resp.StatusCode, Reason: "Unknown"}
+	}
+
+	e := &HTTP2Error{StatusCode: resp.StatusCode, Reason: body.Reason}
+	if body.Timestamp != 0 {
+		ts := time.UnixMilli(body.Timestamp)
+		e.Timestamp = &ts
+	}
+
+	return e
+}
+
+// TokenSigner produces and refreshes the bearer token APNS expects in the
+// authorization header when a provider authenticates with a .p8 signing
+// key instead of a TLS certificate. A signer is safe for concurrent use.
+type TokenSigner struct {
+	KeyID  string
+	TeamID string
+
+	key      *ecdsa.PrivateKey
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewTokenSigner parses a PEM-encoded .p8 ES256 private key as downloaded
+// from the Apple Developer portal.
+func NewTokenSigner(keyID, teamID string, pkcs8PEM []byte) (*TokenSigner, error) {
+	block, _ := pem.Decode(pkcs8PEM)
+	if block == nil {
+		return nil, errors.New("apns: no PEM data found in signing key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("apns: parse signing key: %s", err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns: signing key is not an ECDSA key")
+	}
+
+	return &TokenSigner{KeyID: keyID, TeamID: teamID, key: ecKey}, nil
+}
+
+// Token returns a JWT bearer token, reusing the last one issued as long as
+// it is less than an hour old, since Apple asks providers not to mint a new
+// token more often than that.
+func (s *TokenSigner) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Since(s.issuedAt) < time.Hour {
+		return s.token, nil
+	}
+
+	now := time.Now()
+
+	header, err := json.Marshal(struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{"ES256", s.KeyID})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+	}{s.TeamID, now.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64URLEncode(header) + "." + base64URLEncode(claims)
+
+	sig, err := signES256(s.key, signingInput)
+	if err != nil {
+		return "", err
+	}
+
+	s.token = signingInput + "." + sig
+	s.issuedAt = now
+
+	return s.token, nil
+}
+
+func base64URLEncode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signES256(key *ecdsa.PrivateKey, signingInput string) (string, error) {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	keyBytes := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*keyBytes)
+	r.FillBytes(sig[:keyBytes])
+	s.FillBytes(sig[keyBytes:])
+
+	return base64URLEncode(sig), nil
+}
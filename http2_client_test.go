@@ -0,0 +1,182 @@
+package apns
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T) (*TokenSigner, *ecdsa.PublicKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %s", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %s", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	signer, err := NewTokenSigner("KEYID123", "TEAMID456", pemBytes)
+	if err != nil {
+		t.Fatalf("NewTokenSigner: %s", err)
+	}
+
+	return signer, &key.PublicKey
+}
+
+func TestTokenSignerTokenVerifies(t *testing.T) {
+	signer, pub := newTestSigner(t)
+
+	token, err := signer.Token()
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Token() = %q, want 3 dot-separated parts", token)
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		t.Fatalf("decode header: %s", err)
+	}
+	var h struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil {
+		t.Fatalf("unmarshal header: %s", err)
+	}
+	if h.Alg != "ES256" || h.Kid != "KEYID123" {
+		t.Fatalf("header = %+v, want alg ES256 and kid KEYID123", h)
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decode claims: %s", err)
+	}
+	var c struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+	}
+	if err := json.Unmarshal(claims, &c); err != nil {
+		t.Fatalf("unmarshal claims: %s", err)
+	}
+	if c.Iss != "TEAMID456" {
+		t.Fatalf("claims.Iss = %q, want TEAMID456", c.Iss)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decode signature: %s", err)
+	}
+	if len(sig)%2 != 0 {
+		t.Fatalf("signature length %d is not even", len(sig))
+	}
+	half := len(sig) / 2
+	r := new(big.Int).SetBytes(sig[:half])
+	s := new(big.Int).SetBytes(sig[half:])
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		t.Fatal("signature does not verify against the signer's public key")
+	}
+}
+
+func TestTokenSignerReusesTokenWithinAnHour(t *testing.T) {
+	signer, _ := newTestSigner(t)
+
+	first, err := signer.Token()
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	second, err := signer.Token()
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	if first != second {
+		t.Fatal("Token() minted a new token before an hour had passed")
+	}
+
+	signer.mu.Lock()
+	signer.issuedAt = time.Now().Add(-2 * time.Hour)
+	signer.mu.Unlock()
+
+	third, err := signer.Token()
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+	if third == second {
+		t.Fatal("Token() reused a token issued more than an hour ago")
+	}
+}
+
+func TestParseHTTP2Error(t *testing.T) {
+	body := `{"reason":"BadDeviceToken","timestamp":1700000000000}`
+	resp := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+
+	err := parseHTTP2Error(resp)
+
+	httpErr, ok := err.(*HTTP2Error)
+	if !ok {
+		t.Fatalf("parseHTTP2Error returned %T, want *HTTP2Error", err)
+	}
+	if httpErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("StatusCode = %d, want %d", httpErr.StatusCode, http.StatusBadRequest)
+	}
+	if httpErr.Reason != "BadDeviceToken" {
+		t.Errorf("Reason = %q, want BadDeviceToken", httpErr.Reason)
+	}
+	if httpErr.Timestamp == nil || httpErr.Timestamp.UnixMilli() != 1700000000000 {
+		t.Errorf("Timestamp = %v, want 1700000000000ms", httpErr.Timestamp)
+	}
+	if !httpErr.Unsubscribe() {
+		t.Error("Unsubscribe() = false, want true for BadDeviceToken")
+	}
+}
+
+func TestParseHTTP2ErrorWithoutTimestamp(t *testing.T) {
+	body := `{"reason":"PayloadTooLarge"}`
+	resp := &http.Response{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+
+	err := parseHTTP2Error(resp)
+
+	httpErr, ok := err.(*HTTP2Error)
+	if !ok {
+		t.Fatalf("parseHTTP2Error returned %T, want *HTTP2Error", err)
+	}
+	if httpErr.Timestamp != nil {
+		t.Errorf("Timestamp = %v, want nil", httpErr.Timestamp)
+	}
+	if httpErr.Unsubscribe() {
+		t.Error("Unsubscribe() = true, want false for PayloadTooLarge")
+	}
+	if !strings.Contains(httpErr.Error(), strconv.Itoa(http.StatusRequestEntityTooLarge)) {
+		t.Errorf("Error() = %q, want it to mention the status code", httpErr.Error())
+	}
+}
@@ -1,7 +1,6 @@
 package apns
 
 import (
-	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
@@ -41,24 +40,33 @@ type Alert struct {
 	// Do not add fields without updating the implementation of isZero.
 	Body         string   `json:"body,omitempty"`
 	Title        string   `json:"title,omitempty"`
+	Subtitle     string   `json:"subtitle,omitempty"`
 	Action       string   `json:"action,omitempty"`
 	LocKey       string   `json:"loc-key,omitempty"`
 	LocArgs      []string `json:"loc-args,omitempty"`
+	TitleLocKey  string   `json:"title-loc-key,omitempty"`
+	TitleLocArgs []string `json:"title-loc-args,omitempty"`
 	ActionLocKey string   `json:"action-loc-key,omitempty"`
 	LaunchImage  string   `json:"launch-image,omitempty"`
 }
 
 func (a *Alert) isZero() bool {
-	return len(a.Body) == 0 && len(a.LocKey) == 0 && len(a.LocArgs) == 0 && len(a.ActionLocKey) == 0 && len(a.LaunchImage) == 0
+	return len(a.Body) == 0 && len(a.Subtitle) == 0 && len(a.LocKey) == 0 && len(a.LocArgs) == 0 &&
+		len(a.TitleLocKey) == 0 && len(a.TitleLocArgs) == 0 && len(a.ActionLocKey) == 0 && len(a.LaunchImage) == 0
 }
 
 type APS struct {
-	Alert            Alert
-	Badge            *int // 0 to clear notifications, nil to leave as is.
-	Sound            string
-	ContentAvailable int
-	URLArgs          []string
-	Category         string // requires iOS 8+
+	Alert             Alert
+	Badge             *int // 0 to clear notifications, nil to leave as is.
+	Sound             string
+	ContentAvailable  int
+	MutableContent    int      // 1 to let a notification service extension modify the payload before display, requires iOS 10+
+	ThreadID          string   // groups related notifications into the same visual stack, requires iOS 12+
+	TargetContentID   string   // identifies the window to bring forward when the notification is tapped, requires iOS 13+/macOS 10.15+
+	InterruptionLevel string   // "passive", "active", "time-sensitive", or "critical", requires iOS 15+
+	RelevanceScore    *float64 // 0.0 to 1.0, used to order notifications in a summary, requires iOS 15+
+	URLArgs           []string
+	Category          string // requires iOS 8+
 }
 
 func (aps APS) serializeForAPNS() map[string]interface{} {
@@ -76,6 +84,21 @@ func (aps APS) serializeForAPNS() map[string]interface{} {
 	if aps.ContentAvailable != 0 {
 		data["content-available"] = aps.ContentAvailable
 	}
+	if aps.MutableContent != 0 {
+		data["mutable-content"] = aps.MutableContent
+	}
+	if aps.ThreadID != "" {
+		data["thread-id"] = aps.ThreadID
+	}
+	if aps.TargetContentID != "" {
+		data["target-content-id"] = aps.TargetContentID
+	}
+	if aps.InterruptionLevel != "" {
+		data["interruption-level"] = aps.InterruptionLevel
+	}
+	if aps.RelevanceScore != nil {
+		data["relevance-score"] = aps.RelevanceScore
+	}
 	if aps.Category != "" {
 		data["category"] = aps.Category
 	}
@@ -91,6 +114,15 @@ func (aps APS) MarshalJSONForAPNS() ([]byte, error) {
 	return json.Marshal(aps.serializeForAPNS())
 }
 
+const (
+	// MaxPayloadSize is the largest JSON-serialized payload APNS accepts
+	// for a standard notification.
+	MaxPayloadSize = 4096
+	// MaxPayloadSizeVoIP is the largest JSON-serialized payload APNS
+	// accepts for a PushTypeVoIP notification.
+	MaxPayloadSizeVoIP = 5120
+)
+
 type Payload struct {
 	APS APS
 	// MDM for mobile device management
@@ -98,6 +130,50 @@ type Payload struct {
 	CustomValues map[string]interface{}
 }
 
+// Validate reports ErrPayloadTooLarge if the JSON-serialized payload
+// exceeds maxBytes, letting a sender catch the problem before APNS does.
+func (p *Payload) Validate(maxBytes int) error {
+	j, err := p.MarshalJSONForAPNS()
+	if err != nil {
+		return err
+	}
+	if len(j) > maxBytes {
+		return ErrPayloadTooLarge
+	}
+	return nil
+}
+
+// TruncateBody shortens APS.Alert.Body on UTF-8 rune boundaries, appending
+// "…", until the serialized payload fits within maxBytes. CustomValues and
+// every other APS field are left untouched. It is a no-op if the payload
+// already fits, and returns ErrPayloadTooLarge if the payload still does
+// not fit once every rune of the body has been removed, leaving the body
+// as "…" alone.
+func (p *Payload) TruncateBody(maxBytes int) error {
+	body := []rune(p.APS.Alert.Body)
+
+	j, err := p.MarshalJSONForAPNS()
+	if err != nil {
+		return err
+	}
+
+	for len(j) > maxBytes && len(body) > 0 {
+		body = body[:len(body)-1]
+		p.APS.Alert.Body = string(body) + "…"
+
+		j, err = p.MarshalJSONForAPNS()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(j) > maxBytes {
+		return ErrPayloadTooLarge
+	}
+
+	return nil
+}
+
 func (p *Payload) serializeForAPNS() map[string]interface{} {
 
 	data := make(map[string]interface{})
@@ -131,6 +207,20 @@ func (p *Payload) SetCustomValue(key string, value interface{}) error {
 	return nil
 }
 
+// PushType is Apple's apns-push-type header value, required by the HTTP/2
+// provider API since iOS 13 and recommended for every notification.
+type PushType string
+
+const (
+	PushTypeAlert        PushType = "alert"
+	PushTypeBackground   PushType = "background"
+	PushTypeVoIP         PushType = "voip"
+	PushTypeComplication PushType = "complication"
+	PushTypeFileProvider PushType = "fileprovider"
+	PushTypeMDM          PushType = "mdm"
+	PushTypeLiveActivity PushType = "liveactivity"
+)
+
 type Notification struct {
 	ID          string
 	DeviceToken string
@@ -138,6 +228,75 @@ type Notification struct {
 	Expiration  *time.Time
 	Priority    int
 	Payload     *Payload
+
+	// Topic is the bundle ID (or bundle ID suffixed with a service, e.g.
+	// ".voip") that the notification is sent to. It is only used by the
+	// HTTP/2 provider API, where it becomes the apns-topic header.
+	Topic string
+
+	// CollapseID, if set, becomes the apns-collapse-id header on the
+	// HTTP/2 provider API, letting Apple coalesce multiple notifications
+	// into the one it last received with the same value.
+	CollapseID string
+
+	// PushType becomes the apns-push-type header on the HTTP/2 provider
+	// API. Leave it empty to let Push infer PushTypeBackground or
+	// PushTypeAlert from the payload.
+	PushType PushType
+}
+
+// resolvePushType returns n.PushType, or the type Push infers from the
+// payload when it is left unset.
+func (n Notification) resolvePushType() PushType {
+	if n.PushType != "" {
+		return n.PushType
+	}
+	if n.Payload != nil && n.Payload.APS.ContentAvailable != 0 && n.Payload.APS.Alert.isZero() {
+		return PushTypeBackground
+	}
+	return PushTypeAlert
+}
+
+// Validate reports errors the APNS server would otherwise reject the
+// notification for: a background push that carries a visible alert or
+// lacks content-available: 1, or a payload over the size APNS allows for
+// n's push type.
+func (n Notification) Validate() error {
+	if err := n.validatePushType(); err != nil {
+		return err
+	}
+	if n.Payload != nil {
+		if err := n.Payload.Validate(n.maxPayloadBytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePushType checks the background-push rule in isolation, without
+// marshaling the payload, so callers that already have the serialized
+// bytes in hand (AppendBinary, HTTP2Client.Push) can check payload size
+// against them directly instead of paying for a second marshal through
+// Payload.Validate.
+func (n Notification) validatePushType() error {
+	if n.resolvePushType() == PushTypeBackground && n.Payload != nil {
+		if !n.Payload.APS.Alert.isZero() {
+			return errors.New("apns: background push type cannot include an alert")
+		}
+		if n.Payload.APS.ContentAvailable != 1 {
+			return errors.New("apns: background push type requires content-available: 1")
+		}
+	}
+	return nil
+}
+
+// maxPayloadBytes returns the largest JSON-serialized payload APNS
+// accepts for n's push type.
+func (n Notification) maxPayloadBytes() int {
+	if n.resolvePushType() == PushTypeVoIP {
+		return MaxPayloadSizeVoIP
+	}
+	return MaxPayloadSize
 }
 
 func NewNotification() Notification {
@@ -148,54 +307,98 @@ func NewPayload() *Payload {
 	return &Payload{CustomValues: map[string]interface{}{}}
 }
 
+// ToBinary encodes n as a single legacy binary-protocol frame.
 func (n Notification) ToBinary() ([]byte, error) {
-	b := []byte{}
+	return n.AppendBinary(nil)
+}
+
+// AppendBinary encodes n as a legacy binary-protocol frame and appends it
+// to dst, returning the extended slice. It computes the exact frame size up
+// front and fills a single buffer with binary.BigEndian.PutUint16/PutUint32
+// and copy. Callers that send many notifications can reuse dst (e.g.
+// buf[:0]) to avoid allocating a new frame per call; decoding the device
+// token and marshaling the payload still allocate on every call.
+func (n Notification) AppendBinary(dst []byte) ([]byte, error) {
+	if err := n.validatePushType(); err != nil {
+		return dst, err
+	}
 
 	binTok, err := hex.DecodeString(n.DeviceToken)
 	if err != nil {
-		return b, fmt.Errorf("convert token to hex error: %s", err)
+		return dst, fmt.Errorf("convert token to hex error: %s", err)
+	}
+	if len(binTok) != deviceTokenItemLength {
+		return dst, ErrInvalidTokenSize
 	}
 
 	var j []byte
 	if n.Payload != nil {
-		j, _ = n.Payload.MarshalJSONForAPNS()
+		j, err = n.Payload.MarshalJSONForAPNS()
+		if err != nil {
+			return dst, err
+		}
+		if len(j) > n.maxPayloadBytes() {
+			return dst, ErrPayloadTooLarge
+		}
 	}
 
-	buf := bytes.NewBuffer(b)
+	frameLen := (3 + deviceTokenItemLength) +
+		(3 + len(j)) +
+		(3 + notificationIdentifierItemLength) +
+		(3 + expirationDateItemLength) +
+		(3 + priorityItemLength)
+
+	total := 5 + frameLen
+
+	start := len(dst)
+	if cap(dst)-start >= total {
+		dst = dst[:start+total]
+	} else {
+		grown := make([]byte, start+total)
+		copy(grown, dst)
+		dst = grown
+	}
+	buf := dst[start:]
+
+	buf[0] = commandID
+	binary.BigEndian.PutUint32(buf[1:5], uint32(frameLen))
+	offset := 5
 
 	// Token
-	binary.Write(buf, binary.BigEndian, uint8(deviceTokenItemID))
-	binary.Write(buf, binary.BigEndian, uint16(deviceTokenItemLength))
-	binary.Write(buf, binary.BigEndian, binTok)
+	buf[offset] = deviceTokenItemID
+	binary.BigEndian.PutUint16(buf[offset+1:offset+3], deviceTokenItemLength)
+	offset += 3
+	offset += copy(buf[offset:], binTok)
 
 	// Payload
-	binary.Write(buf, binary.BigEndian, uint8(payloadItemID))
-	binary.Write(buf, binary.BigEndian, uint16(len(j)))
-	binary.Write(buf, binary.BigEndian, j)
+	buf[offset] = payloadItemID
+	binary.BigEndian.PutUint16(buf[offset+1:offset+3], uint16(len(j)))
+	offset += 3
+	offset += copy(buf[offset:], j)
 
 	// Identifier
-	binary.Write(buf, binary.BigEndian, uint8(notificationIdentifierItemID))
-	binary.Write(buf, binary.BigEndian, uint16(notificationIdentifierItemLength))
-	binary.Write(buf, binary.BigEndian, uint32(n.Identifier))
+	buf[offset] = notificationIdentifierItemID
+	binary.BigEndian.PutUint16(buf[offset+1:offset+3], notificationIdentifierItemLength)
+	offset += 3
+	binary.BigEndian.PutUint32(buf[offset:offset+4], n.Identifier)
+	offset += 4
 
 	// Expiry
-	binary.Write(buf, binary.BigEndian, uint8(expirationDateItemID))
-	binary.Write(buf, binary.BigEndian, uint16(expirationDateItemLength))
-	if n.Expiration == nil {
-		binary.Write(buf, binary.BigEndian, uint32(0))
-	} else {
-		binary.Write(buf, binary.BigEndian, uint32(n.Expiration.Unix()))
+	buf[offset] = expirationDateItemID
+	binary.BigEndian.PutUint16(buf[offset+1:offset+3], expirationDateItemLength)
+	offset += 3
+	var expiry uint32
+	if n.Expiration != nil {
+		expiry = uint32(n.Expiration.Unix())
 	}
+	binary.BigEndian.PutUint32(buf[offset:offset+4], expiry)
+	offset += 4
 
 	// Priority
-	binary.Write(buf, binary.BigEndian, uint8(priorityItemID))
-	binary.Write(buf, binary.BigEndian, uint16(priorityItemLength))
-	binary.Write(buf, binary.BigEndian, uint8(n.Priority))
-
-	framebuf := bytes.NewBuffer([]byte{})
-	binary.Write(framebuf, binary.BigEndian, uint8(commandID))
-	binary.Write(framebuf, binary.BigEndian, uint32(buf.Len()))
-	binary.Write(framebuf, binary.BigEndian, buf.Bytes())
+	buf[offset] = priorityItemID
+	binary.BigEndian.PutUint16(buf[offset+1:offset+3], priorityItemLength)
+	offset += 3
+	buf[offset] = uint8(n.Priority)
 
-	return framebuf.Bytes(), nil
+	return dst, nil
 }
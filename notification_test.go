@@ -0,0 +1,137 @@
+package apns
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exampleNotification() Notification {
+	n := NewNotification()
+	n.DeviceToken = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	n.Identifier = 1
+	exp := time.Unix(1700000000, 0)
+	n.Expiration = &exp
+	n.Priority = PriorityImmediate
+	n.Payload.APS.Alert.Body = "hello"
+	return n
+}
+
+func TestAppendBinaryMatchesToBinary(t *testing.T) {
+	n := exampleNotification()
+
+	want, err := n.ToBinary()
+	if err != nil {
+		t.Fatalf("ToBinary: %s", err)
+	}
+
+	got, err := n.AppendBinary(nil)
+	if err != nil {
+		t.Fatalf("AppendBinary: %s", err)
+	}
+
+	if string(got) != string(want) {
+		t.Fatalf("AppendBinary produced a different frame than ToBinary:\ngot:  %x\nwant: %x", got, want)
+	}
+}
+
+func TestAppendBinaryRejectsWrongTokenSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"too long", strings.Repeat("aa", 200)},
+		{"too short", "aaaa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := exampleNotification()
+			n.DeviceToken = tt.token
+
+			if _, err := n.AppendBinary(nil); !errors.Is(err, ErrInvalidTokenSize) {
+				t.Fatalf("AppendBinary() = %v, want ErrInvalidTokenSize", err)
+			}
+			if _, err := n.ToBinary(); !errors.Is(err, ErrInvalidTokenSize) {
+				t.Fatalf("ToBinary() = %v, want ErrInvalidTokenSize", err)
+			}
+		})
+	}
+}
+
+func TestPayloadValidate(t *testing.T) {
+	p := NewPayload()
+	p.APS.Alert.Body = strings.Repeat("a", 100)
+
+	if err := p.Validate(1000); err != nil {
+		t.Fatalf("Validate(1000) = %s, want nil", err)
+	}
+
+	if err := p.Validate(10); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("Validate(10) = %v, want ErrPayloadTooLarge", err)
+	}
+}
+
+func TestPayloadTruncateBody(t *testing.T) {
+	p := NewPayload()
+	p.APS.Alert.Body = strings.Repeat("a", 100)
+	p.CustomValues["id"] = "keep-me"
+
+	if err := p.TruncateBody(60); err != nil {
+		t.Fatalf("TruncateBody: %s", err)
+	}
+
+	j, err := p.MarshalJSONForAPNS()
+	if err != nil {
+		t.Fatalf("MarshalJSONForAPNS: %s", err)
+	}
+	if len(j) > 60 {
+		t.Fatalf("payload is %d bytes after truncation, want <= 60", len(j))
+	}
+	if !strings.HasSuffix(p.APS.Alert.Body, "…") {
+		t.Fatalf("Body = %q, want it to end with an ellipsis", p.APS.Alert.Body)
+	}
+	if p.CustomValues["id"] != "keep-me" {
+		t.Fatalf("CustomValues were modified by TruncateBody")
+	}
+}
+
+// TestAppendBinaryDoesNotDoubleMarshalPayload guards against a regression
+// where AppendBinary's validation step and its frame-writing step each
+// marshal the payload to JSON independently. It allows some headroom for
+// hex.DecodeString and json.Marshal's own internal allocations, but fails
+// if the payload is being serialized more than once per call.
+func TestAppendBinaryDoesNotDoubleMarshalPayload(t *testing.T) {
+	n := exampleNotification()
+	buf := make([]byte, 0, 512)
+
+	const maxAllocsPerCall = 20
+
+	allocs := testing.AllocsPerRun(100, func() {
+		var err error
+		buf, err = n.AppendBinary(buf[:0])
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if allocs > maxAllocsPerCall {
+		t.Errorf("AppendBinary allocated %.0f times per call, want <= %d; payload is likely being marshaled more than once", allocs, maxAllocsPerCall)
+	}
+}
+
+func BenchmarkAppendBinary(b *testing.B) {
+	n := exampleNotification()
+	buf := make([]byte, 0, 512)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		buf, err = n.AppendBinary(buf[:0])
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}